@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+const defaultConfigFile = "config.json"
+
+// Config holds the weather/UV provider selection, site coordinates, and
+// provider credentials. It replaces the previous hard-coded token/URL
+// constants so deployments can switch providers without a rebuild.
+type Config struct {
+	Provider       string               `json:"provider"` // "openuv" (default), "openweathermap", or "local"
+	Latitude       float64              `json:"latitude"`
+	Longitude      float64              `json:"longitude"`
+	OpenUV         OpenUVConfig         `json:"openuv"`
+	OpenWeatherMap OpenWeatherMapConfig `json:"openweathermap"`
+}
+
+// OpenUVConfig holds credentials for the OpenUV provider.
+type OpenUVConfig struct {
+	Token string `json:"token"`
+}
+
+// OpenWeatherMapConfig holds credentials for the OpenWeatherMap OneCall provider.
+type OpenWeatherMapConfig struct {
+	APIKey string `json:"apiKey"`
+}
+
+// loadConfig reads the weather provider configuration from path.
+func loadConfig(path string) (Config, error) {
+	var cfg Config
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("reading config file %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// newWeatherProvider builds the WeatherProvider selected by cfg.Provider.
+func newWeatherProvider(cfg Config) (WeatherProvider, error) {
+	switch cfg.Provider {
+	case "", "openuv":
+		return &OpenUVProvider{Token: cfg.OpenUV.Token, Lat: cfg.Latitude, Lng: cfg.Longitude}, nil
+	case "openweathermap":
+		return &OpenWeatherMapProvider{APIKey: cfg.OpenWeatherMap.APIKey, Lat: cfg.Latitude, Lng: cfg.Longitude}, nil
+	case "local":
+		return &LocalAstronomicalProvider{Lat: cfg.Latitude, Lng: cfg.Longitude}, nil
+	default:
+		return nil, fmt.Errorf("unknown weather provider %q", cfg.Provider)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -8,9 +9,11 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
 	"regexp"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/gocolly/colly"
@@ -18,11 +21,18 @@ import (
 
 const panelFile = "panels.json"
 const globalFile = "global.json"
-const maxPower = 250
 
-/* openuv api token*/
-const token = "0e065c55d8005cb5a09ed714f631492b"
-const openUvURL = "https://api.openuv.io/api/v1/uv?lat=43.5856664&lng=3.7536762"
+// Defaults applied to panels.json entries that predate the physics-based
+// theoreticalPower model (see power.go) and so have Tilt/Azimuth/AreaM2/
+// Efficiency/TempCoeff all zero. Without these, such a panel's theoretical
+// output is permanently floored at 0W regardless of actual sun conditions.
+const (
+	defaultTilt       = 30.0
+	defaultAzimuth    = 180.0
+	defaultAreaM2     = 1.6
+	defaultEfficiency = 0.2
+	defaultTempCoeff  = -0.004
+)
 
 /*
 The LiveData struct
@@ -36,10 +46,22 @@ type LiveData struct {
 
 /*
 The Panel struct
+Tilt and Azimuth describe the panel's mounting (degrees from horizontal, degrees
+from north) and feed the incidence-angle calculation in theoreticalPower.
+AreaM2 and Efficiency are the panel's physical area (m²) and nameplate
+conversion efficiency (0-1). TempCoeff is the manufacturer's power
+temperature coefficient per °C (typically negative, e.g. -0.004).
+Performance is the panel's latest actual-vs-theoretical output ratio, in percent.
 */
 type Panel struct {
 	InverterID     string
 	HistoricalData []LiveData
+	Tilt           float64
+	Azimuth        float64
+	AreaM2         float64
+	Efficiency     float64
+	TempCoeff      float64
+	Performance    float64
 }
 
 /*
@@ -47,6 +69,7 @@ SolarData struct
 The solar altitude angle, αs, is the angle between the horizontal and the line to the sun. It is the complement of the zenith angle θz.
 The solar azimuth angle, γs, is the angular displacement from south of the projection of beam radiation on the horizontal plane;
 displacements east of south are negative and west of south are positive.
+CloudCover, Sunrise, Sunset, and Forecast are only populated by providers that support them (currently OpenWeatherMapProvider).
 */
 type SolarData struct {
 	Uvi         float64
@@ -54,6 +77,10 @@ type SolarData struct {
 	UvMaxTime   string
 	SunAltitude float64
 	SunAzimuth  float64
+	CloudCover  float64
+	Sunrise     string
+	Sunset      string
+	Forecast    []ForecastEntry `json:",omitempty"`
 }
 
 /*
@@ -67,13 +94,18 @@ type GlobalData struct {
 	SolarData   SolarData
 }
 
-func parseRealTime() map[string]LiveData {
+func parseRealTime(ctx context.Context) (map[string]LiveData, error) {
 	c := colly.NewCollector()
 
 	re := regexp.MustCompile(`[-]?\d[\d,]*[\.]?[\d{2}]*`)
 
 	dataMap := make(map[string]LiveData)
 
+	var scrapeErr error
+	c.OnError(func(r *colly.Response, err error) {
+		scrapeErr = fmt.Errorf("scraping inverter page: %w", err)
+	})
+
 	c.OnHTML("body > table > tbody > tr", func(e *colly.HTMLElement) {
 
 		liveData := LiveData{}
@@ -100,8 +132,23 @@ func parseRealTime() map[string]LiveData {
 		dataMap[InverterID] = liveData
 	})
 
-	c.Visit("http://192.168.1.68/cgi-bin/parameters")
-	return dataMap
+	done := make(chan struct{})
+	go func() {
+		c.Visit("http://192.168.1.68/cgi-bin/parameters")
+		close(done)
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("scraping inverter page: %w", ctx.Err())
+	case <-done:
+	}
+
+	if scrapeErr != nil {
+		return nil, scrapeErr
+	}
+
+	return dataMap, nil
 }
 
 func loadPanelsFromJSON() []Panel {
@@ -116,13 +163,27 @@ func loadPanelsFromJSON() []Panel {
 
 	json.Unmarshal(byteValue, &panels)
 
+	for i := range panels {
+		if panels[i].AreaM2 == 0 || panels[i].Efficiency == 0 {
+			log.Printf("panel %s has no AreaM2/Efficiency configured; seeding defaults so theoreticalPower isn't stuck at 0W", panels[i].InverterID)
+			panels[i].Tilt = defaultTilt
+			panels[i].Azimuth = defaultAzimuth
+			panels[i].AreaM2 = defaultAreaM2
+			panels[i].Efficiency = defaultEfficiency
+			panels[i].TempCoeff = defaultTempCoeff
+		}
+	}
+
 	return panels
 }
 
-func savePanels(panels []Panel) {
-	file, _ := json.MarshalIndent(panels, "", " ")
+func savePanels(panels []Panel) error {
+	file, err := json.MarshalIndent(panels, "", " ")
+	if err != nil {
+		return fmt.Errorf("marshaling panels: %w", err)
+	}
 
-	_ = ioutil.WriteFile(panelFile, file, 0644)
+	return ioutil.WriteFile(panelFile, file, 0644)
 }
 
 func loadGlobalFromJSON() []GlobalData {
@@ -140,72 +201,109 @@ func loadGlobalFromJSON() []GlobalData {
 	return globals
 }
 
-func saveGlobal(globals []GlobalData) {
-	file, _ := json.MarshalIndent(globals, "", " ")
+func saveGlobal(globals []GlobalData) error {
+	file, err := json.MarshalIndent(globals, "", " ")
+	if err != nil {
+		return fmt.Errorf("marshaling globals: %w", err)
+	}
 
-	_ = ioutil.WriteFile(globalFile, file, 0644)
+	return ioutil.WriteFile(globalFile, file, 0644)
 }
 
-func getData(url string, token string) map[string]interface{} {
+func getData(ctx context.Context, url string, token string) (map[string]interface{}, error) {
 
 	// Create a new request using http
-	req, _ := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building request for %s: %w", url, err)
+	}
 
 	// add authorization header to the req
 	req.Header.Add("x-access-token", token)
 
 	// Send req using http Client
-	client := &http.Client{}
-	resp, getErr := client.Do(req)
-	if getErr != nil {
-		log.Println("Error on response.\n[ERRO] -", getErr)
-	}
-	if resp.Body != nil {
-		defer resp.Body.Close()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", url, err)
 	}
+	defer resp.Body.Close()
 
-	body, readErr := ioutil.ReadAll(resp.Body)
-	if readErr != nil {
-		log.Fatal(readErr)
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response from %s: %w", url, err)
 	}
 
 	var data map[string]interface{}
-	parseErr := json.Unmarshal([]byte(body), &data)
-	if parseErr != nil {
-		panic(parseErr)
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("parsing response from %s: %w", url, err)
 	}
-	return data
+	return data, nil
 }
 
-func makeSolarData(data map[string]interface{}) SolarData {
+func makeSolarData(data map[string]interface{}) (SolarData, error) {
 	solarData := SolarData{}
 
-	result := data["result"].(map[string]interface{})
+	result, ok := data["result"].(map[string]interface{})
+	if !ok {
+		if message, hasMessage := data["message"].(string); hasMessage {
+			code := fmt.Sprintf("%v", data["status"])
+			return solarData, &ProviderError{Provider: "openuv", Code: code, Message: message}
+		}
+		return solarData, fmt.Errorf("openuv: unexpected response shape, missing \"result\": %v", data)
+	}
 
-	solarData.Uvi = result["uv"].(float64)
-	solarData.UvMax = result["uv_max"].(float64)
-	solarData.UvMaxTime = result["uv_max_time"].(string)
+	solarData.Uvi, _ = result["uv"].(float64)
+	solarData.UvMax, _ = result["uv_max"].(float64)
+	solarData.UvMaxTime, _ = result["uv_max_time"].(string)
 
-	//sunInfo := result["sun_info"].(map[string]interface{})
-	sunPosition := result["sun_info"].(map[string]interface{})["sun_position"].(map[string]interface{})
+	sunInfo, ok := result["sun_info"].(map[string]interface{})
+	if !ok {
+		return solarData, fmt.Errorf("openuv: unexpected response shape, missing \"sun_info\"")
+	}
+	sunPosition, ok := sunInfo["sun_position"].(map[string]interface{})
+	if !ok {
+		return solarData, fmt.Errorf("openuv: unexpected response shape, missing \"sun_position\"")
+	}
 
-	solarData.SunAltitude = sunPosition["altitude"].(float64)
-	solarData.SunAzimuth = sunPosition["azimuth"].(float64)
+	solarData.SunAltitude, _ = sunPosition["altitude"].(float64)
+	solarData.SunAzimuth, _ = sunPosition["azimuth"].(float64)
 
-	return solarData
+	return solarData, nil
 }
 
-func main() {
-	//HANDLING FLAGS
-	isVerboseMode := flag.Bool("v", false, "verbose mode")
-	flag.Parse()
+// scrapeOnce performs a single scrape/compute/persist cycle: it reads live
+// inverter data and weather/solar data from provider, computes each panel's
+// performance, and returns the refreshed state so callers (the one-shot CLI
+// path and the daemon poller) can both report on it. When store is non-nil,
+// each sample is appended to it instead of being folded into
+// panels[i].HistoricalData and the ever-growing global.json array, so
+// memory usage and file size no longer scale with history length.
+func scrapeOnce(ctx context.Context, provider WeatherProvider, store Store, isVerboseMode bool) ([]Panel, GlobalData, map[string]LiveData, map[string]float64, error) {
+	//DATA FROM SOLAR PANELS, retried with backoff since a single transient
+	//blip scraping the inverter page shouldn't fail the whole poll cycle
+	var liveDataMap map[string]LiveData
+	err := retryWithBackoff(ctx, 3, 500*time.Millisecond, func() error {
+		var scrapeErr error
+		liveDataMap, scrapeErr = parseRealTime(ctx)
+		return scrapeErr
+	})
+	if err != nil {
+		return nil, GlobalData{}, nil, nil, fmt.Errorf("scraping inverter data: %w", err)
+	}
 
-	//DATA FROM SOLAR PANELS
-	liveDataMap := parseRealTime()
-	//UVI DATA FROM openweathermap API
-	solarData := makeSolarData(getData(openUvURL, token))
+	//UVI/SOLAR DATA FROM the configured weather provider, retried with backoff
+	//since transient network failures shouldn't take the whole cycle down
+	var solarData SolarData
+	err = retryWithBackoff(ctx, 3, 500*time.Millisecond, func() error {
+		var fetchErr error
+		solarData, fetchErr = provider.FetchSolarData(ctx)
+		return fetchErr
+	})
+	if err != nil {
+		return nil, GlobalData{}, nil, nil, fmt.Errorf("fetching solar data: %w", err)
+	}
 
-	if *isVerboseMode {
+	if isVerboseMode {
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetIndent("", "  ")
 		// Dump json to the standard output
@@ -217,26 +315,199 @@ func main() {
 
 	globalData := GlobalData{}
 	totalOutput := new(int64)
+	theoreticalTotalOutput := 0.0
+	theoreticalByInverter := make(map[string]float64, len(panels))
 	for i, panel := range panels {
-		panels[i].HistoricalData = append(panel.HistoricalData, liveDataMap[panel.InverterID])
-		*totalOutput += liveDataMap[panel.InverterID].CurrentPower
-	}
+		live := liveDataMap[panel.InverterID]
+		if store == nil {
+			panels[i].HistoricalData = append(panel.HistoricalData, live)
+		}
+		*totalOutput += live.CurrentPower
+
+		theoretical := theoreticalPower(panel, solarData, float64(live.Temperature))
+		theoreticalByInverter[panel.InverterID] = theoretical
+		theoreticalTotalOutput += theoretical
 
-	theoreticalTotalOutput := int64(len(panels)) * maxPower
+		if theoretical > 0 {
+			panels[i].Performance = float64(live.CurrentPower) / theoretical * 100
+		} else {
+			panels[i].Performance = 0
+		}
+	}
 
 	globalData.Power = *totalOutput
-	globalData.Performance = (float64(*totalOutput) / float64(theoreticalTotalOutput) * 100)
-	globalData.Date = time.Now().Format("2006-01-02 15:04:05") //Format YYYY-MM-DD hh:mm:ss
+	if theoreticalTotalOutput > 0 {
+		globalData.Performance = float64(*totalOutput) / theoreticalTotalOutput * 100
+	} else {
+		globalData.Performance = 0
+	}
+	now := time.Now()
+	globalData.Date = now.Format("2006-01-02 15:04:05") //Format YYYY-MM-DD hh:mm:ss
 	globalData.SolarData = solarData
 
-	globals := loadGlobalFromJSON()
+	fmt.Println(fmt.Sprintf("%s - %d W - %.2f %% capacity", now.Format("2006-01-02 15:04:05"), *totalOutput, globalData.Performance))
+
+	if store != nil {
+		if err := store.SaveMeasurements(now, panels, liveDataMap); err != nil {
+			return nil, GlobalData{}, nil, nil, fmt.Errorf("saving measurements: %w", err)
+		}
+		if err := store.SaveGlobal(GlobalMeasurement{
+			Timestamp:   now,
+			Power:       globalData.Power,
+			Performance: globalData.Performance,
+			Uvi:         solarData.Uvi,
+			SunAltitude: solarData.SunAltitude,
+			SunAzimuth:  solarData.SunAzimuth,
+		}); err != nil {
+			return nil, GlobalData{}, nil, nil, fmt.Errorf("saving global measurement: %w", err)
+		}
+		if err := savePanels(panels); err != nil {
+			return nil, GlobalData{}, nil, nil, fmt.Errorf("saving panels: %w", err)
+		}
+
+		return panels, globalData, liveDataMap, theoreticalByInverter, nil
+	}
 
+	globals := loadGlobalFromJSON()
 	globals = append(globals, globalData)
 
-	fmt.Println(fmt.Sprintf("%s - %d W - %.2f %% capacity", time.Now().Format("2006-01-02 15:04:05"), *totalOutput, globalData.Performance))
+	if err := savePanels(panels); err != nil {
+		return nil, GlobalData{}, nil, nil, fmt.Errorf("saving panels: %w", err)
+	}
+	if err := saveGlobal(globals); err != nil {
+		return nil, GlobalData{}, nil, nil, fmt.Errorf("saving globals: %w", err)
+	}
+
+	return panels, globalData, liveDataMap, theoreticalByInverter, nil
+}
+
+func main() {
+	//HANDLING FLAGS
+	isVerboseMode := flag.Bool("v", false, "verbose mode")
+	listenAddr := flag.String("listen", "", "address to serve Prometheus metrics and JSON endpoints on (e.g. :9090); enables daemon mode")
+	pollInterval := flag.Duration("interval", 60*time.Second, "polling interval in daemon mode")
+	configFile := flag.String("config", defaultConfigFile, "path to the weather provider config file")
+	importSurfradFile := flag.String("import-surfrad", "", "import a SURFRAD-style text file into global.json and exit")
+	exportSurfradFile := flag.String("export-surfrad", "", "export global.json to a SURFRAD-style text file and exit")
+	surfradStation := flag.String("surfrad-station", "goSolarPanel", "station name written to the SURFRAD export header")
+	migrateStoreSpec := flag.String("migrate-store", "", "migrate panels.json/global.json into a time-series store (e.g. \"sqlite:history.db\" or \"jsonl:history.jsonl\") and exit")
+	storeSpec := flag.String("store", "", "time-series store to append ongoing samples to instead of rewriting panels.json/global.json (e.g. \"sqlite:history.db\" or \"jsonl:history.jsonl\")")
+	queryStoreSpec := flag.String("query-store", "", "time-series store to read for -query-daily-performance/-query-power-at (e.g. \"sqlite:history.db\" or \"jsonl:history.jsonl\")")
+	queryDailyPerformanceDays := flag.Int("query-daily-performance", 0, "print average daily performance for the last N days from -query-store and exit")
+	queryPowerAt := flag.String("query-power-at", "", "print an inverter's power closest to a given time from -query-store, as \"<inverter_id>@<RFC3339 time>\" (e.g. \"inv-1@2024-03-20T14:00:00Z\"), and exit")
+	flag.Parse()
+
+	if *migrateStoreSpec != "" {
+		store, err := newStore(*migrateStoreSpec)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer store.Close()
+
+		if err := migrateJSONToStore(store); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *queryDailyPerformanceDays != 0 || *queryPowerAt != "" {
+		if *queryStoreSpec == "" {
+			log.Fatal("-query-daily-performance/-query-power-at require -query-store")
+		}
+		store, err := newStore(*queryStoreSpec)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer store.Close()
+
+		if *queryDailyPerformanceDays != 0 {
+			since := time.Now().AddDate(0, 0, -*queryDailyPerformanceDays)
+			results, err := store.AverageDailyPerformance(since)
+			if err != nil {
+				log.Fatal(err)
+			}
+			for _, r := range results {
+				fmt.Printf("%s\t%.2f%%\n", r.Date, r.AvgPerformance)
+			}
+		}
+
+		if *queryPowerAt != "" {
+			inverterID, atStr, found := strings.Cut(*queryPowerAt, "@")
+			if !found {
+				log.Fatalf("-query-power-at must be \"<inverter_id>@<RFC3339 time>\", got %q", *queryPowerAt)
+			}
+			at, err := time.Parse(time.RFC3339, atStr)
+			if err != nil {
+				log.Fatalf("parsing time %q: %v", atStr, err)
+			}
+			measurement, found, err := store.PowerAt(inverterID, at)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if !found {
+				fmt.Printf("no measurements found for %s\n", inverterID)
+			} else {
+				fmt.Printf("%s at %s: %dW\n", inverterID, measurement.Timestamp.Format(time.RFC3339), measurement.Power)
+			}
+		}
+
+		return
+	}
+
+	if *importSurfradFile != "" {
+		imported, err := importSurfrad(*importSurfradFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		globals := loadGlobalFromJSON()
+		if err := saveGlobal(append(globals, imported...)); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *exportSurfradFile != "" {
+		if err := exportSurfrad(*exportSurfradFile, *surfradStation, loadGlobalFromJSON()); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	savePanels(panels)
-	saveGlobal(globals)
+	provider, err := newWeatherProvider(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var store Store
+	if *storeSpec != "" {
+		store, err = newStore(*storeSpec)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer store.Close()
+	}
+
+	if *listenAddr != "" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		if err := runDaemon(ctx, provider, store, *listenAddr, *pollInterval, *isVerboseMode); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if _, _, _, _, err := scrapeOnce(ctx, provider, store, *isVerboseMode); err != nil {
+		log.Fatal(err)
+	}
 }
 
 //tarif kWh (12 kVa)
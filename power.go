@@ -0,0 +1,53 @@
+package main
+
+import "math"
+
+// solarConstant is the mean extraterrestrial solar irradiance, in W/m².
+const solarConstant = 1361.0
+
+// clearSkyFactor is an empirical atmospheric transmittance standing in for a
+// full clear-sky model; it is further attenuated by cloud cover when a
+// WeatherProvider reports one.
+const clearSkyFactor = 0.75
+
+// theoreticalPower estimates a single panel's expected output, in watts,
+// under the given solar conditions and ambient temperature:
+//
+//	GHI   = sin(altitude) * solarConstant * clearSkyFactor * cloud attenuation
+//	power = GHI * cos(incidence) * AreaM2 * Efficiency * (1 + TempCoeff*(temp-25))
+//
+// incidence is the angle between the panel's normal (from its Tilt/Azimuth)
+// and the sun vector (from solar.SunAltitude/SunAzimuth). Power is floored
+// at zero when the sun is below the horizon or behind the panel.
+func theoreticalPower(panel Panel, solar SolarData, temperatureCelsius float64) float64 {
+	if solar.SunAltitude <= 0 {
+		return 0
+	}
+
+	altitudeRad := solar.SunAltitude * math.Pi / 180
+	tiltRad := panel.Tilt * math.Pi / 180
+	panelAzimuthRad := panel.Azimuth * math.Pi / 180
+	sunAzimuthRad := solar.SunAzimuth * math.Pi / 180
+
+	cosIncidence := math.Cos(tiltRad)*math.Sin(altitudeRad) +
+		math.Sin(tiltRad)*math.Cos(altitudeRad)*math.Cos(sunAzimuthRad-panelAzimuthRad)
+	if cosIncidence < 0 {
+		return 0
+	}
+
+	cloudAttenuation := 1.0
+	if solar.CloudCover > 0 {
+		cloudAttenuation = 1 - solar.CloudCover/100*0.75
+	}
+
+	ghi := math.Sin(altitudeRad) * solarConstant * clearSkyFactor * cloudAttenuation
+
+	tempDerate := 1 + panel.TempCoeff*(temperatureCelsius-25)
+
+	power := ghi * cosIncidence * panel.AreaM2 * panel.Efficiency * tempDerate
+	if power < 0 {
+		return 0
+	}
+
+	return power
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTheoreticalPowerZeroWhenSunBelowHorizon(t *testing.T) {
+	panel := Panel{Tilt: 30, Azimuth: 180, AreaM2: 1.6, Efficiency: 0.2, TempCoeff: -0.004}
+	solar := SolarData{SunAltitude: -5, SunAzimuth: 180}
+
+	if got := theoreticalPower(panel, solar, 25); got != 0 {
+		t.Errorf("theoreticalPower() with sun below horizon = %v, want 0", got)
+	}
+}
+
+func TestTheoreticalPowerZeroWhenPanelHasNoArea(t *testing.T) {
+	// A panel migrated from the pre-physics-model schema has Tilt/Azimuth/
+	// AreaM2/Efficiency/TempCoeff all defaulted to zero.
+	panel := Panel{InverterID: "legacy-panel"}
+	solar := SolarData{SunAltitude: 45, SunAzimuth: 180}
+
+	if got := theoreticalPower(panel, solar, 25); got != 0 {
+		t.Errorf("theoreticalPower() for a zero-area panel = %v, want 0", got)
+	}
+}
+
+func TestTheoreticalPowerFacingSunExceedsFacingAway(t *testing.T) {
+	solar := SolarData{SunAltitude: 45, SunAzimuth: 180}
+
+	facingSun := Panel{Tilt: 45, Azimuth: 180, AreaM2: 1.6, Efficiency: 0.2, TempCoeff: -0.004}
+	facingAway := Panel{Tilt: 45, Azimuth: 0, AreaM2: 1.6, Efficiency: 0.2, TempCoeff: -0.004}
+
+	towardsSun := theoreticalPower(facingSun, solar, 25)
+	awayFromSun := theoreticalPower(facingAway, solar, 25)
+
+	if towardsSun <= awayFromSun {
+		t.Errorf("expected a panel facing the sun (%v W) to outproduce one facing away (%v W)", towardsSun, awayFromSun)
+	}
+}
+
+func TestTheoreticalPowerCloudCoverAttenuates(t *testing.T) {
+	panel := Panel{Tilt: 0, Azimuth: 180, AreaM2: 1.6, Efficiency: 0.2, TempCoeff: -0.004}
+	clear := SolarData{SunAltitude: 60, SunAzimuth: 180}
+	cloudy := SolarData{SunAltitude: 60, SunAzimuth: 180, CloudCover: 100}
+
+	clearPower := theoreticalPower(panel, clear, 25)
+	cloudyPower := theoreticalPower(panel, cloudy, 25)
+
+	if cloudyPower >= clearPower {
+		t.Errorf("full cloud cover (%v W) should attenuate output below a clear sky (%v W)", cloudyPower, clearPower)
+	}
+}
+
+func TestSolarPositionNoonNearEquatorIsNearlyOverhead(t *testing.T) {
+	// 2024-03-20 is the March equinox; at local solar noon on the equator
+	// and prime meridian the sun should be close to directly overhead.
+	noon := time.Date(2024, time.March, 20, 12, 0, 0, 0, time.UTC)
+
+	altitude, _ := solarPosition(0, 0, noon)
+
+	if altitude < 60 {
+		t.Errorf("altitude at local solar noon near the equator = %v, want > 60 degrees", altitude)
+	}
+}
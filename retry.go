@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// ProviderError represents a structured error envelope returned by a
+// weather/UV provider instead of the expected payload, e.g. OpenUV's
+// quota-exceeded response or OpenWeatherMap's {cod, message} shape. It is
+// treated as permanent by retryWithBackoff: retrying won't fix a bad API
+// key or an exceeded quota.
+type ProviderError struct {
+	Provider string
+	Code     string
+	Message  string
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s: %s (code %s)", e.Provider, e.Message, e.Code)
+}
+
+// retryWithBackoff calls fn until it succeeds, ctx is cancelled, maxAttempts
+// is reached, or fn returns a *ProviderError (which retrying can't fix). The
+// delay between attempts doubles each time starting from baseDelay, with up
+// to 50% jitter added to avoid thundering-herd retries.
+func retryWithBackoff(ctx context.Context, maxAttempts int, baseDelay time.Duration, fn func() error) error {
+	var lastErr error
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			delay := baseDelay * time.Duration(int64(1)<<uint(attempt-1))
+			jitter := time.Duration(rand.Int63n(int64(delay)/2 + 1))
+
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("retry cancelled: %w", ctx.Err())
+			case <-time.After(delay + jitter):
+			}
+		}
+
+		lastErr = fn()
+		if lastErr == nil {
+			return nil
+		}
+
+		var provErr *ProviderError
+		if errors.As(lastErr, &provErr) {
+			return lastErr
+		}
+	}
+
+	return fmt.Errorf("giving up after %d attempts: %w", maxAttempts, lastErr)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRetryWithBackoffSucceedsFirstTry(t *testing.T) {
+	calls := 0
+
+	err := retryWithBackoff(context.Background(), 3, time.Millisecond, func() error {
+		calls++
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("retryWithBackoff() = %v, want nil", err)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1", calls)
+	}
+}
+
+func TestRetryWithBackoffRetriesTransientFailures(t *testing.T) {
+	calls := 0
+
+	err := retryWithBackoff(context.Background(), 3, time.Millisecond, func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("transient failure")
+		}
+		return nil
+	})
+
+	if err != nil {
+		t.Fatalf("retryWithBackoff() = %v, want nil", err)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3", calls)
+	}
+}
+
+func TestRetryWithBackoffGivesUpAfterMaxAttempts(t *testing.T) {
+	calls := 0
+	wantErr := errors.New("persistent failure")
+
+	err := retryWithBackoff(context.Background(), 3, time.Millisecond, func() error {
+		calls++
+		return wantErr
+	})
+
+	if err == nil {
+		t.Fatal("retryWithBackoff() = nil, want an error")
+	}
+	if !errors.Is(err, wantErr) {
+		t.Errorf("retryWithBackoff() error = %v, want it to wrap %v", err, wantErr)
+	}
+	if calls != 3 {
+		t.Errorf("calls = %d, want 3 (maxAttempts)", calls)
+	}
+}
+
+func TestRetryWithBackoffStopsOnProviderError(t *testing.T) {
+	calls := 0
+	provErr := &ProviderError{Provider: "openuv", Code: "403", Message: "quota exceeded"}
+
+	err := retryWithBackoff(context.Background(), 3, time.Millisecond, func() error {
+		calls++
+		return provErr
+	})
+
+	if !errors.Is(err, provErr) {
+		t.Errorf("retryWithBackoff() error = %v, want %v", err, provErr)
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1 (no retry on a permanent provider error)", calls)
+	}
+}
+
+func TestRetryWithBackoffCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+	err := retryWithBackoff(ctx, 3, time.Millisecond, func() error {
+		calls++
+		return errors.New("transient failure")
+	})
+
+	if err == nil {
+		t.Fatal("retryWithBackoff() = nil, want an error for a cancelled context")
+	}
+	if calls != 1 {
+		t.Errorf("calls = %d, want 1 (cancelled before the first retry sleep)", calls)
+	}
+}
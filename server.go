@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	panelPowerGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "solarpanel_inverter_power_watts",
+		Help: "Current output power reported by an inverter, in watts.",
+	}, []string{"inverter_id"})
+
+	panelVoltageGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "solarpanel_inverter_voltage_volts",
+		Help: "Current voltage reported by an inverter, in volts.",
+	}, []string{"inverter_id"})
+
+	panelTemperatureGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "solarpanel_inverter_temperature_celsius",
+		Help: "Current temperature reported by an inverter, in degrees Celsius.",
+	}, []string{"inverter_id"})
+
+	panelTheoreticalPowerGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "solarpanel_inverter_theoretical_power_watts",
+		Help: "Theoretical maximum power for an inverter's panel under current conditions, in watts.",
+	}, []string{"inverter_id"})
+
+	globalPowerGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "solarpanel_global_power_watts",
+		Help: "Total current output power across all panels, in watts.",
+	})
+
+	globalPerformanceGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "solarpanel_global_performance_percent",
+		Help: "Actual total output power as a percentage (0-100) of theoretical total output power.",
+	})
+
+	globalUviGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "solarpanel_uv_index",
+		Help: "Current UV index at the installation site.",
+	})
+
+	globalSunAltitudeGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "solarpanel_sun_altitude_degrees",
+		Help: "Current solar altitude angle, in degrees.",
+	})
+
+	globalSunAzimuthGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "solarpanel_sun_azimuth_degrees",
+		Help: "Current solar azimuth angle, in degrees.",
+	})
+
+	scrapeFailuresCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "solarpanel_scrape_failures_total",
+		Help: "Number of poll cycles that failed to scrape inverter or weather data.",
+	})
+)
+
+// server holds the latest scrape results in memory so the JSON endpoints
+// can be served without triggering a new scrape on every request.
+type server struct {
+	mu     sync.RWMutex
+	panels []Panel
+	global GlobalData
+}
+
+func (s *server) update(panels []Panel, global GlobalData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.panels = panels
+	s.global = global
+}
+
+func (s *server) updateMetrics(panels []Panel, liveDataMap map[string]LiveData, global GlobalData, theoreticalByInverter map[string]float64) {
+	for _, panel := range panels {
+		live := liveDataMap[panel.InverterID]
+		panelPowerGauge.WithLabelValues(panel.InverterID).Set(float64(live.CurrentPower))
+		panelVoltageGauge.WithLabelValues(panel.InverterID).Set(float64(live.Voltage))
+		panelTemperatureGauge.WithLabelValues(panel.InverterID).Set(float64(live.Temperature))
+		if theoretical, ok := theoreticalByInverter[panel.InverterID]; ok {
+			panelTheoreticalPowerGauge.WithLabelValues(panel.InverterID).Set(theoretical)
+		}
+	}
+
+	globalPowerGauge.Set(float64(global.Power))
+	globalPerformanceGauge.Set(global.Performance)
+	globalUviGauge.Set(global.SolarData.Uvi)
+	globalSunAltitudeGauge.Set(global.SolarData.SunAltitude)
+	globalSunAzimuthGauge.Set(global.SolarData.SunAzimuth)
+}
+
+func (s *server) panelsHandler(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.panels)
+}
+
+func (s *server) globalHandler(w http.ResponseWriter, r *http.Request) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.global)
+}
+
+// runDaemon scrapes the panels once immediately, then every interval, and
+// serves Prometheus metrics plus JSON snapshots on listenAddr until ctx is
+// cancelled.
+func runDaemon(ctx context.Context, provider WeatherProvider, store Store, listenAddr string, interval time.Duration, isVerboseMode bool) error {
+	srv := &server{}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/panels", srv.panelsHandler)
+	mux.HandleFunc("/global", srv.globalHandler)
+
+	httpServer := &http.Server{Addr: listenAddr, Handler: mux}
+
+	poll := func() {
+		scrapeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+
+		panels, global, liveDataMap, theoreticalByInverter, err := scrapeOnce(scrapeCtx, provider, store, isVerboseMode)
+		if err != nil {
+			scrapeFailuresCounter.Inc()
+			log.Println("scrape failed:", err)
+			return
+		}
+		srv.update(panels, global)
+		srv.updateMetrics(panels, liveDataMap, global, theoreticalByInverter)
+	}
+	poll()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				poll()
+			}
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("listening on %s (polling every %s)", listenAddr, interval)
+
+	if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	// go-sqlite3 is a CGO binding: building or testing this package requires
+	// CGO_ENABLED=1 and a C toolchain (e.g. gcc) available on PATH.
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// Measurement is one inverter sample persisted by a Store.
+type Measurement struct {
+	InverterID string
+	Timestamp  time.Time
+	Power      int64
+	Voltage    int64
+	Temp       int64
+}
+
+// GlobalMeasurement is one whole-installation sample persisted by a Store.
+type GlobalMeasurement struct {
+	Timestamp   time.Time
+	Power       int64
+	Performance float64
+	Uvi         float64
+	SunAltitude float64
+	SunAzimuth  float64
+}
+
+// DailyPerformance is one day's average global performance, in percent.
+type DailyPerformance struct {
+	Date           string
+	AvgPerformance float64
+}
+
+// Store persists time-series measurements so history no longer has to be
+// held entirely in memory and rewritten wholesale on every tick the way
+// panels.json/global.json are. It also answers the historical queries that
+// motivated moving off panels.json/global.json in the first place, such as
+// "average daily performance for the last 30 days" and "what was this
+// inverter's power at a given time".
+type Store interface {
+	SaveMeasurements(ts time.Time, panels []Panel, liveDataMap map[string]LiveData) error
+	SaveGlobal(g GlobalMeasurement) error
+	AverageDailyPerformance(since time.Time) ([]DailyPerformance, error)
+	PowerAt(inverterID string, at time.Time) (Measurement, bool, error)
+	Close() error
+}
+
+// newStore builds a Store from a "driver:path" spec, e.g. "sqlite:history.db"
+// or "jsonl:history.jsonl".
+func newStore(spec string) (Store, error) {
+	driver, path, found := strings.Cut(spec, ":")
+	if !found {
+		return nil, fmt.Errorf("store spec %q must be \"driver:path\" (sqlite or jsonl)", spec)
+	}
+
+	switch driver {
+	case "sqlite":
+		return NewSQLiteStore(path)
+	case "jsonl":
+		return NewJSONLStore(path)
+	default:
+		return nil, fmt.Errorf("unknown store driver %q", driver)
+	}
+}
+
+// SQLiteStore persists measurements into a SQLite database, indexed for
+// queries like "average daily performance for the last 30 days".
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore opens (creating if necessary) a SQLite store at path.
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, fmt.Errorf("opening sqlite store %s: %w", path, err)
+	}
+
+	schema := `
+	CREATE TABLE IF NOT EXISTS measurements (
+		inverter_id TEXT NOT NULL,
+		ts          TEXT NOT NULL,
+		power       INTEGER NOT NULL,
+		voltage     INTEGER NOT NULL,
+		temp        INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_measurements_inverter_ts ON measurements(inverter_id, ts);
+
+	CREATE TABLE IF NOT EXISTS globals (
+		ts   TEXT NOT NULL,
+		power INTEGER NOT NULL,
+		perf  REAL NOT NULL,
+		uvi   REAL NOT NULL,
+		alt   REAL NOT NULL,
+		az    REAL NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_globals_ts ON globals(ts);
+	`
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("creating schema in %s: %w", path, err)
+	}
+
+	return &SQLiteStore{db: db}, nil
+}
+
+func (s *SQLiteStore) SaveMeasurements(ts time.Time, panels []Panel, liveDataMap map[string]LiveData) error {
+	tsStr := ts.Format(time.RFC3339)
+	for _, panel := range panels {
+		live := liveDataMap[panel.InverterID]
+		_, err := s.db.Exec(
+			"INSERT INTO measurements (inverter_id, ts, power, voltage, temp) VALUES (?, ?, ?, ?, ?)",
+			panel.InverterID, tsStr, live.CurrentPower, live.Voltage, live.Temperature,
+		)
+		if err != nil {
+			return fmt.Errorf("inserting measurement for %s: %w", panel.InverterID, err)
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) SaveGlobal(g GlobalMeasurement) error {
+	_, err := s.db.Exec(
+		"INSERT INTO globals (ts, power, perf, uvi, alt, az) VALUES (?, ?, ?, ?, ?, ?)",
+		g.Timestamp.Format(time.RFC3339), g.Power, g.Performance, g.Uvi, g.SunAltitude, g.SunAzimuth,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting global measurement: %w", err)
+	}
+	return nil
+}
+
+// AverageDailyPerformance returns one row per calendar day (UTC) with at
+// least one global sample at or after since, averaging GlobalMeasurement.Performance
+// across that day's samples.
+func (s *SQLiteStore) AverageDailyPerformance(since time.Time) ([]DailyPerformance, error) {
+	rows, err := s.db.Query(
+		"SELECT date(ts) AS day, AVG(perf) FROM globals WHERE ts >= ? GROUP BY day ORDER BY day",
+		since.Format(time.RFC3339),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying daily performance since %s: %w", since, err)
+	}
+	defer rows.Close()
+
+	var results []DailyPerformance
+	for rows.Next() {
+		var d DailyPerformance
+		if err := rows.Scan(&d.Date, &d.AvgPerformance); err != nil {
+			return nil, fmt.Errorf("scanning daily performance row: %w", err)
+		}
+		results = append(results, d)
+	}
+	return results, rows.Err()
+}
+
+// PowerAt returns the measurement for inverterID closest in time to at. The
+// second return value is false if inverterID has no measurements at all.
+func (s *SQLiteStore) PowerAt(inverterID string, at time.Time) (Measurement, bool, error) {
+	row := s.db.QueryRow(
+		`SELECT inverter_id, ts, power, voltage, temp FROM measurements
+		 WHERE inverter_id = ?
+		 ORDER BY ABS(strftime('%s', ts) - strftime('%s', ?)) ASC
+		 LIMIT 1`,
+		inverterID, at.Format(time.RFC3339),
+	)
+
+	var m Measurement
+	var tsStr string
+	if err := row.Scan(&m.InverterID, &tsStr, &m.Power, &m.Voltage, &m.Temp); err != nil {
+		if err == sql.ErrNoRows {
+			return Measurement{}, false, nil
+		}
+		return Measurement{}, false, fmt.Errorf("querying power for %s at %s: %w", inverterID, at, err)
+	}
+
+	ts, err := time.Parse(time.RFC3339, tsStr)
+	if err != nil {
+		return Measurement{}, false, fmt.Errorf("parsing timestamp %q: %w", tsStr, err)
+	}
+	m.Timestamp = ts
+
+	return m, true, nil
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// jsonlRecord is one line of a JSONLStore file: either a measurement or a
+// global sample, tagged by Type.
+type jsonlRecord struct {
+	Type        string             `json:"type"`
+	Measurement *Measurement       `json:"measurement,omitempty"`
+	Global      *GlobalMeasurement `json:"global,omitempty"`
+}
+
+// JSONLStore appends one JSON object per line instead of rewriting an
+// ever-growing JSON array on every tick.
+type JSONLStore struct {
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewJSONLStore opens (creating if necessary) an append-only JSONL store at path.
+func NewJSONLStore(path string) (*JSONLStore, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("opening jsonl store %s: %w", path, err)
+	}
+
+	return &JSONLStore{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+func (s *JSONLStore) SaveMeasurements(ts time.Time, panels []Panel, liveDataMap map[string]LiveData) error {
+	for _, panel := range panels {
+		live := liveDataMap[panel.InverterID]
+		m := Measurement{InverterID: panel.InverterID, Timestamp: ts, Power: live.CurrentPower, Voltage: live.Voltage, Temp: live.Temperature}
+		if err := s.enc.Encode(jsonlRecord{Type: "measurement", Measurement: &m}); err != nil {
+			return fmt.Errorf("appending measurement for %s: %w", panel.InverterID, err)
+		}
+	}
+	return nil
+}
+
+func (s *JSONLStore) SaveGlobal(g GlobalMeasurement) error {
+	if err := s.enc.Encode(jsonlRecord{Type: "global", Global: &g}); err != nil {
+		return fmt.Errorf("appending global measurement: %w", err)
+	}
+	return nil
+}
+
+// AverageDailyPerformance scans the store file for global samples at or
+// after since, averaging GlobalMeasurement.Performance per calendar day
+// (UTC). Unlike SQLiteStore.AverageDailyPerformance this is a linear scan,
+// not an indexed lookup: JSONLStore trades query speed for append-only
+// simplicity.
+func (s *JSONLStore) AverageDailyPerformance(since time.Time) ([]DailyPerformance, error) {
+	records, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+
+	sums := make(map[string]float64)
+	counts := make(map[string]int)
+	for _, r := range records {
+		if r.Global == nil || r.Global.Timestamp.Before(since) {
+			continue
+		}
+		day := r.Global.Timestamp.UTC().Format("2006-01-02")
+		sums[day] += r.Global.Performance
+		counts[day]++
+	}
+
+	days := make([]string, 0, len(sums))
+	for day := range sums {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+
+	results := make([]DailyPerformance, 0, len(days))
+	for _, day := range days {
+		results = append(results, DailyPerformance{Date: day, AvgPerformance: sums[day] / float64(counts[day])})
+	}
+	return results, nil
+}
+
+// PowerAt scans the store file for the measurement closest in time to at for
+// inverterID. See AverageDailyPerformance for why this is a scan, not an
+// indexed lookup.
+func (s *JSONLStore) PowerAt(inverterID string, at time.Time) (Measurement, bool, error) {
+	records, err := s.readAll()
+	if err != nil {
+		return Measurement{}, false, err
+	}
+
+	var best Measurement
+	var bestDiff time.Duration
+	found := false
+	for _, r := range records {
+		if r.Measurement == nil || r.Measurement.InverterID != inverterID {
+			continue
+		}
+		diff := r.Measurement.Timestamp.Sub(at)
+		if diff < 0 {
+			diff = -diff
+		}
+		if !found || diff < bestDiff {
+			best, bestDiff, found = *r.Measurement, diff, true
+		}
+	}
+	return best, found, nil
+}
+
+// readAll reads every record in the store file from the start, independent
+// of the append-only encoder's current write position.
+func (s *JSONLStore) readAll() ([]jsonlRecord, error) {
+	file, err := os.Open(s.file.Name())
+	if err != nil {
+		return nil, fmt.Errorf("reading jsonl store %s: %w", s.file.Name(), err)
+	}
+	defer file.Close()
+
+	var records []jsonlRecord
+	dec := json.NewDecoder(file)
+	for {
+		var r jsonlRecord
+		if err := dec.Decode(&r); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("decoding jsonl record: %w", err)
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+func (s *JSONLStore) Close() error {
+	return s.file.Close()
+}
+
+// migrateJSONToStore converts the legacy panels.json/global.json files into
+// store, one row per historical sample.
+func migrateJSONToStore(store Store) error {
+	panels := loadPanelsFromJSON()
+	globals := loadGlobalFromJSON()
+
+	for _, panel := range panels {
+		for _, live := range panel.HistoricalData {
+			ts, err := time.Parse("2006-01-02 15:04:05", live.Date)
+			if err != nil {
+				ts = time.Now()
+			}
+			if err := store.SaveMeasurements(ts, []Panel{panel}, map[string]LiveData{panel.InverterID: live}); err != nil {
+				return fmt.Errorf("migrating measurements for %s: %w", panel.InverterID, err)
+			}
+		}
+	}
+
+	for _, g := range globals {
+		ts, err := time.Parse("2006-01-02 15:04:05", g.Date)
+		if err != nil {
+			ts = time.Now()
+		}
+		if err := store.SaveGlobal(GlobalMeasurement{
+			Timestamp:   ts,
+			Power:       g.Power,
+			Performance: g.Performance,
+			Uvi:         g.SolarData.Uvi,
+			SunAltitude: g.SolarData.SunAltitude,
+			SunAzimuth:  g.SolarData.SunAzimuth,
+		}); err != nil {
+			return fmt.Errorf("migrating global measurement: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// surfradHeaderComment documents the column order written/expected in each
+// data row, following the station-header-plus-fixed-columns shape used by
+// NOAA's SURFRAD radiometric network (https://gml.noaa.gov/grad/surfrad/).
+// We export a reduced column set: year, day-of-year, month, day, hour,
+// minute, decimal time, solar zenith angle, downwelling solar (dw_solar,
+// our aggregate panel power stands in for it), direct-normal and diffuse
+// (left at zero, we don't separate them), and temperature.
+const surfradHeaderComment = "# year jday month day hour min dt zen dw_solar direct_n diffuse temp"
+
+// SurfradRecord is one per-minute SURFRAD-style observation.
+type SurfradRecord struct {
+	Year        int
+	JulianDay   int
+	Month       int
+	Day         int
+	Hour        int
+	Minute      int
+	DecimalTime float64
+	Zenith      float64
+	DwSolar     float64
+	DirectN     float64
+	Diffuse     float64
+	Temperature float64
+}
+
+// globalToSurfradRecord maps a GlobalData sample onto a SURFRAD-style row:
+// total panel power stands in for dw_solar, and zenith is derived from our
+// own SunAltitude (zenith = 90° - altitude).
+func globalToSurfradRecord(g GlobalData) (SurfradRecord, error) {
+	t, err := time.Parse("2006-01-02 15:04:05", g.Date)
+	if err != nil {
+		return SurfradRecord{}, fmt.Errorf("parsing date %q: %w", g.Date, err)
+	}
+
+	return SurfradRecord{
+		Year:        t.Year(),
+		JulianDay:   t.YearDay(),
+		Month:       int(t.Month()),
+		Day:         t.Day(),
+		Hour:        t.Hour(),
+		Minute:      t.Minute(),
+		DecimalTime: float64(t.YearDay()) + (float64(t.Hour())+float64(t.Minute())/60)/24,
+		Zenith:      90 - g.SolarData.SunAltitude,
+		DwSolar:     float64(g.Power),
+		Temperature: 0,
+	}, nil
+}
+
+// surfradRecordToGlobal maps a SURFRAD-style row back onto a GlobalData
+// sample, inverting globalToSurfradRecord.
+func surfradRecordToGlobal(r SurfradRecord) GlobalData {
+	date := time.Date(r.Year, 1, 1, r.Hour, r.Minute, 0, 0, time.UTC).AddDate(0, 0, r.JulianDay-1)
+
+	return GlobalData{
+		Date:  date.Format("2006-01-02 15:04:05"),
+		Power: int64(r.DwSolar),
+		SolarData: SolarData{
+			SunAltitude: 90 - r.Zenith,
+		},
+	}
+}
+
+// exportSurfrad writes globals to path in SURFRAD-style fixed-column text
+// format, prefixed by a station header line.
+func exportSurfrad(path string, station string, globals []GlobalData) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("creating %s: %w", path, err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	fmt.Fprintln(w, station)
+	fmt.Fprintln(w, surfradHeaderComment)
+
+	for _, g := range globals {
+		record, err := globalToSurfradRecord(g)
+		if err != nil {
+			return fmt.Errorf("exporting %s: %w", path, err)
+		}
+
+		fmt.Fprintf(w, "%4d %3d %2d %2d %2d %2d %9.4f %7.2f %8.2f %8.2f %8.2f %6.2f\n",
+			record.Year, record.JulianDay, record.Month, record.Day, record.Hour, record.Minute,
+			record.DecimalTime, record.Zenith, record.DwSolar, record.DirectN, record.Diffuse, record.Temperature)
+	}
+
+	return w.Flush()
+}
+
+// importSurfrad reads a SURFRAD-style fixed-column text file and returns
+// its data rows as GlobalData samples, skipping the station header and
+// column comment line.
+func importSurfrad(path string) ([]GlobalData, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+
+	var globals []GlobalData
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if lineNum == 1 {
+			// station header line, not a data row
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 12 {
+			return nil, fmt.Errorf("%s:%d: expected 12 columns, got %d", path, lineNum, len(fields))
+		}
+
+		record, err := parseSurfradRecord(fields)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, lineNum, err)
+		}
+
+		globals = append(globals, surfradRecordToGlobal(record))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+
+	return globals, nil
+}
+
+func parseSurfradRecord(fields []string) (SurfradRecord, error) {
+	ints := make([]int, 6)
+	for i := 0; i < 6; i++ {
+		v, err := strconv.Atoi(fields[i])
+		if err != nil {
+			return SurfradRecord{}, fmt.Errorf("parsing column %d (%q): %w", i+1, fields[i], err)
+		}
+		ints[i] = v
+	}
+
+	floats := make([]float64, 6)
+	for i := 0; i < 6; i++ {
+		v, err := strconv.ParseFloat(fields[6+i], 64)
+		if err != nil {
+			return SurfradRecord{}, fmt.Errorf("parsing column %d (%q): %w", 7+i, fields[6+i], err)
+		}
+		floats[i] = v
+	}
+
+	return SurfradRecord{
+		Year: ints[0], JulianDay: ints[1], Month: ints[2], Day: ints[3], Hour: ints[4], Minute: ints[5],
+		DecimalTime: floats[0], Zenith: floats[1], DwSolar: floats[2], DirectN: floats[3], Diffuse: floats[4], Temperature: floats[5],
+	}, nil
+}
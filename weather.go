@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"time"
+)
+
+// WeatherProvider supplies UV/solar conditions for the configured site.
+// Implementations may call a remote API (OpenUVProvider, OpenWeatherMapProvider)
+// or compute pure solar geometry offline (LocalAstronomicalProvider). ctx
+// bounds how long a network call is allowed to take.
+type WeatherProvider interface {
+	FetchSolarData(ctx context.Context) (SolarData, error)
+}
+
+// ForecastEntry is one hourly forecast sample from a WeatherProvider that
+// supports forecasts (currently only OpenWeatherMapProvider).
+type ForecastEntry struct {
+	Time        string
+	Uvi         float64
+	CloudCover  float64
+	Temperature float64
+}
+
+// OpenUVProvider fetches the current UV index and sun position from the
+// OpenUV API (https://www.openuv.io).
+type OpenUVProvider struct {
+	Token string
+	Lat   float64
+	Lng   float64
+}
+
+func (p *OpenUVProvider) FetchSolarData(ctx context.Context) (SolarData, error) {
+	url := fmt.Sprintf("https://api.openuv.io/api/v1/uv?lat=%f&lng=%f", p.Lat, p.Lng)
+
+	data, err := getData(ctx, url, p.Token)
+	if err != nil {
+		return SolarData{}, err
+	}
+
+	return makeSolarData(data)
+}
+
+// OpenWeatherMapProvider fetches UV index, cloud cover, sunrise/sunset, and
+// an hourly forecast from the OpenWeatherMap OneCall API.
+type OpenWeatherMapProvider struct {
+	APIKey string
+	Lat    float64
+	Lng    float64
+}
+
+func (p *OpenWeatherMapProvider) FetchSolarData(ctx context.Context) (SolarData, error) {
+	url := fmt.Sprintf("https://api.openweathermap.org/data/3.0/onecall?lat=%f&lon=%f&units=metric&appid=%s", p.Lat, p.Lng, p.APIKey)
+
+	data, err := fetchJSON(ctx, url)
+	if err != nil {
+		return SolarData{}, err
+	}
+
+	if cod, isError := data["cod"]; isError {
+		message, _ := data["message"].(string)
+		return SolarData{}, &ProviderError{Provider: "openweathermap", Code: fmt.Sprintf("%v", cod), Message: message}
+	}
+
+	current, ok := data["current"].(map[string]interface{})
+	if !ok {
+		return SolarData{}, fmt.Errorf("openweathermap: unexpected response shape, missing \"current\"")
+	}
+
+	solarData := SolarData{}
+	solarData.Uvi, _ = current["uvi"].(float64)
+	solarData.CloudCover, _ = current["clouds"].(float64)
+
+	if sunrise, ok := current["sunrise"].(float64); ok {
+		solarData.Sunrise = time.Unix(int64(sunrise), 0).Format("2006-01-02 15:04:05")
+	}
+	if sunset, ok := current["sunset"].(float64); ok {
+		solarData.Sunset = time.Unix(int64(sunset), 0).Format("2006-01-02 15:04:05")
+	}
+
+	solarData.SunAltitude, solarData.SunAzimuth = solarPosition(p.Lat, p.Lng, time.Now())
+
+	if hourly, ok := data["hourly"].([]interface{}); ok {
+		for _, h := range hourly {
+			hour, ok := h.(map[string]interface{})
+			if !ok {
+				continue
+			}
+
+			entry := ForecastEntry{}
+			if ts, ok := hour["dt"].(float64); ok {
+				entry.Time = time.Unix(int64(ts), 0).Format("2006-01-02 15:04:05")
+			}
+			entry.Uvi, _ = hour["uvi"].(float64)
+			entry.CloudCover, _ = hour["clouds"].(float64)
+			entry.Temperature, _ = hour["temp"].(float64)
+
+			solarData.Forecast = append(solarData.Forecast, entry)
+		}
+	}
+
+	return solarData, nil
+}
+
+// LocalAstronomicalProvider computes solar altitude/azimuth directly from
+// latitude, longitude, and the current time using NOAA's simplified solar
+// position formulas. It makes no network calls, so UV index and cloud
+// cover are left at zero.
+type LocalAstronomicalProvider struct {
+	Lat float64
+	Lng float64
+}
+
+func (p *LocalAstronomicalProvider) FetchSolarData(ctx context.Context) (SolarData, error) {
+	altitude, azimuth := solarPosition(p.Lat, p.Lng, time.Now())
+
+	return SolarData{
+		SunAltitude: altitude,
+		SunAzimuth:  azimuth,
+	}, nil
+}
+
+// solarPosition computes the solar altitude and azimuth, in degrees, for the
+// given latitude/longitude (degrees) and time, using NOAA's simplified solar
+// position formulas evaluated in UTC.
+func solarPosition(lat, lng float64, t time.Time) (altitude, azimuth float64) {
+	t = t.UTC()
+
+	dayFraction := (float64(t.Hour())*60 + float64(t.Minute())) / 1440
+	gamma := 2 * math.Pi / 365 * (float64(t.YearDay()) - 1 + dayFraction)
+
+	eqTime := 229.18 * (0.000075 + 0.001868*math.Cos(gamma) - 0.032077*math.Sin(gamma) -
+		0.014615*math.Cos(2*gamma) - 0.040849*math.Sin(2*gamma))
+	decl := 0.006918 - 0.399912*math.Cos(gamma) + 0.070257*math.Sin(gamma) -
+		0.006758*math.Cos(2*gamma) + 0.000907*math.Sin(2*gamma) -
+		0.002697*math.Cos(3*gamma) + 0.00148*math.Sin(3*gamma)
+
+	trueSolarTime := float64(t.Hour())*60 + float64(t.Minute()) + float64(t.Second())/60 + eqTime + 4*lng
+	hourAngle := trueSolarTime/4 - 180
+
+	latRad := lat * math.Pi / 180
+	haRad := hourAngle * math.Pi / 180
+
+	cosZenith := clampUnit(math.Sin(latRad)*math.Sin(decl) + math.Cos(latRad)*math.Cos(decl)*math.Cos(haRad))
+	zenith := math.Acos(cosZenith)
+	altitude = 90 - zenith*180/math.Pi
+
+	sinZenith := math.Sin(zenith)
+	if sinZenith < 1e-9 {
+		// Sun directly overhead (zenith ~ 0, e.g. solar noon on the equator at
+		// equinox): the azimuth formula divides by sin(zenith) and is
+		// undefined here, so leave azimuth at due south rather than
+		// propagating a NaN/Inf.
+		return altitude, 0
+	}
+
+	cosAz := clampUnit((math.Sin(decl) - math.Sin(latRad)*cosZenith) / (math.Cos(latRad) * sinZenith))
+	az := math.Acos(cosAz) * 180 / math.Pi
+	if hourAngle > 0 {
+		az = 360 - az
+	}
+
+	return altitude, az
+}
+
+// clampUnit clamps x to [-1, 1] to guard acos against floating-point drift
+// pushing a valid cosine just outside its domain.
+func clampUnit(x float64) float64 {
+	if x > 1 {
+		return 1
+	}
+	if x < -1 {
+		return -1
+	}
+	return x
+}
+
+// fetchJSON issues a context-bound GET request and decodes the JSON response body.
+func fetchJSON(ctx context.Context, url string) (map[string]interface{}, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("building request for %s: %w", url, err)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response from %s: %w", url, err)
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("parsing response from %s: %w", url, err)
+	}
+
+	return data, nil
+}